@@ -0,0 +1,275 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DiffKind uint8
+
+const (
+	DiffSame DiffKind = iota
+	DiffAdded
+	DiffRemoved
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffSame:
+		return " "
+	case DiffAdded:
+		return "+"
+	case DiffRemoved:
+		return "-"
+	case DiffChanged:
+		return "~"
+	}
+
+	return "?"
+}
+
+// DiffEntry is one line of an instruction level diff. OldPC and NewPC are -1 when
+// the instruction is not present on that side.
+type DiffEntry struct {
+	Kind  DiffKind
+	OldPC int
+	NewPC int
+	Old   string
+	New   string
+}
+
+func (e DiffEntry) String() string {
+	switch e.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%v %04x: %v", e.Kind, e.NewPC, e.New)
+	case DiffRemoved:
+		return fmt.Sprintf("%v %04x: %v", e.Kind, e.OldPC, e.Old)
+	case DiffChanged:
+		return fmt.Sprintf("%v %04x: %v => %04x: %v", e.Kind, e.OldPC, e.Old, e.NewPC, e.New)
+	}
+
+	return fmt.Sprintf("%v %04x: %v", e.Kind, e.NewPC, e.New)
+}
+
+// diffKeys returns the text used to compare instructions. Label targets are replaced with the
+// distance in instructions to the target, so that shifted program counters compare equal.
+func diffKeys(instructions []decodedInstruction) []string {
+	indexForPC := make(map[uint16]int, len(instructions))
+	for i, decoded := range instructions {
+		indexForPC[decoded.pc.Value()] = i
+	}
+
+	keys := make([]string, len(instructions))
+
+	for i, decoded := range instructions {
+		key := decoded.String()
+
+		for _, label := range decoded.labels {
+			target := label.DefinedProgramCounter().Value()
+
+			var relative string
+			if targetIndex, found := indexForPC[target]; found {
+				relative = fmt.Sprintf("[label %+d]", targetIndex-i)
+			} else {
+				relative = fmt.Sprintf("[label pc%+d]", int(target)-int(decoded.pc.Value()))
+			}
+
+			key = strings.Replace(key, label.String(), relative, 1)
+		}
+
+		keys[i] = key
+	}
+
+	return keys
+}
+
+// lcsLengths returns, for every j, the length of the longest common subsequence of a and b[:j].
+func lcsLengths(a []string, b []string) []int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				current[j+1] = previous[j] + 1
+			} else if previous[j+1] >= current[j] {
+				current[j+1] = previous[j+1]
+			} else {
+				current[j+1] = current[j]
+			}
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous
+}
+
+func reversed(lines []string) []string {
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[len(lines)-1-i] = line
+	}
+
+	return result
+}
+
+type diffMatch struct {
+	oldIndex int
+	newIndex int
+}
+
+// longestCommonSubsequence uses Hirschberg's algorithm, so only linear space is needed.
+// The matches are returned in order, with indices offset by oldOffset and newOffset.
+func longestCommonSubsequence(a []string, b []string, oldOffset int, newOffset int) []diffMatch {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+
+	if len(a) == 1 {
+		for j, line := range b {
+			if line == a[0] {
+				return []diffMatch{{oldIndex: oldOffset, newIndex: newOffset + j}}
+			}
+		}
+
+		return nil
+	}
+
+	middle := len(a) / 2
+	forward := lcsLengths(a[:middle], b)
+	backward := lcsLengths(reversed(a[middle:]), reversed(b))
+
+	split := 0
+	best := -1
+
+	for j := 0; j <= len(b); j++ {
+		length := forward[j] + backward[len(b)-j]
+		if length > best {
+			best = length
+			split = j
+		}
+	}
+
+	matches := longestCommonSubsequence(a[:middle], b[:split], oldOffset, newOffset)
+
+	return append(matches, longestCommonSubsequence(a[middle:], b[split:], oldOffset+middle, newOffset+split)...)
+}
+
+// appendGapEntries adds the instructions between two matches. A removed instruction is only
+// reported as changed when an added instruction has the same command, otherwise the
+// instructions are reported as removed and added.
+func appendGapEntries(entries []DiffEntry, removed []decodedInstruction, added []decodedInstruction) []DiffEntry {
+	addedIndex := 0
+
+	for _, oldDecoded := range removed {
+		sameCommandIndex := -1
+
+		for k := addedIndex; k < len(added); k++ {
+			if added[k].cmd == oldDecoded.cmd {
+				sameCommandIndex = k
+				break
+			}
+		}
+
+		if sameCommandIndex == -1 {
+			entries = append(entries, DiffEntry{Kind: DiffRemoved, OldPC: int(oldDecoded.pc.Value()), NewPC: -1, Old: oldDecoded.String()})
+			continue
+		}
+
+		for ; addedIndex < sameCommandIndex; addedIndex++ {
+			newDecoded := added[addedIndex]
+			entries = append(entries, DiffEntry{Kind: DiffAdded, OldPC: -1, NewPC: int(newDecoded.pc.Value()), New: newDecoded.String()})
+		}
+
+		newDecoded := added[sameCommandIndex]
+		entries = append(entries, DiffEntry{
+			Kind: DiffChanged, OldPC: int(oldDecoded.pc.Value()), NewPC: int(newDecoded.pc.Value()),
+			Old: oldDecoded.String(), New: newDecoded.String(),
+		})
+		addedIndex = sameCommandIndex + 1
+	}
+
+	for ; addedIndex < len(added); addedIndex++ {
+		newDecoded := added[addedIndex]
+		entries = append(entries, DiffEntry{Kind: DiffAdded, OldPC: -1, NewPC: int(newDecoded.pc.Value()), New: newDecoded.String()})
+	}
+
+	return entries
+}
+
+// Diff disassembles both octet streams and compares them instruction by instruction.
+// The instructions are aligned using the longest common subsequence of the commands and their
+// operands, with branch targets compared as instruction distances, so shifted program counters
+// do not show up as differences. The Old and New texts still show the absolute program counters.
+func Diff(oldOctets []byte, newOctets []byte) ([]DiffEntry, error) {
+	oldInstructions, err := decodeInstructions(oldOctets, false)
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+
+	newInstructions, err := decodeInstructions(newOctets, false)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+
+	oldKeys := diffKeys(oldInstructions)
+	newKeys := diffKeys(newInstructions)
+
+	prefix := 0
+	for prefix < len(oldKeys) && prefix < len(newKeys) && oldKeys[prefix] == newKeys[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(oldKeys)-prefix && suffix < len(newKeys)-prefix &&
+		oldKeys[len(oldKeys)-1-suffix] == newKeys[len(newKeys)-1-suffix] {
+		suffix++
+	}
+
+	var matches []diffMatch
+
+	for i := 0; i < prefix; i++ {
+		matches = append(matches, diffMatch{oldIndex: i, newIndex: i})
+	}
+
+	matches = append(matches, longestCommonSubsequence(oldKeys[prefix:len(oldKeys)-suffix], newKeys[prefix:len(newKeys)-suffix], prefix, prefix)...)
+
+	for i := suffix; i > 0; i-- {
+		matches = append(matches, diffMatch{oldIndex: len(oldKeys) - i, newIndex: len(newKeys) - i})
+	}
+
+	// The end of both streams acts as a final match, so the trailing differences are flushed
+	matches = append(matches, diffMatch{oldIndex: len(oldKeys), newIndex: len(newKeys)})
+
+	var entries []DiffEntry
+
+	i := 0
+	j := 0
+
+	for _, match := range matches {
+		entries = appendGapEntries(entries, oldInstructions[i:match.oldIndex], newInstructions[j:match.newIndex])
+
+		if match.oldIndex == len(oldKeys) {
+			break
+		}
+
+		oldDecoded := oldInstructions[match.oldIndex]
+		newDecoded := newInstructions[match.newIndex]
+		entries = append(entries, DiffEntry{
+			Kind: DiffSame, OldPC: int(oldDecoded.pc.Value()), NewPC: int(newDecoded.pc.Value()),
+			Old: oldDecoded.String(), New: newDecoded.String(),
+		})
+
+		i = match.oldIndex + 1
+		j = match.newIndex + 1
+	}
+
+	return entries, nil
+}
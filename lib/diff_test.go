@@ -0,0 +1,81 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	oldOctets, err := hex.DecodeString("17000000000100000002000000000b00270000000002000000010006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newOctets, err := hex.DecodeString("23000000002a000000170000000001000000270000000002000000020006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Diff(oldOctets, newOctets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", entries)
+
+	const expectedOutput = `[+ 0000: ldi 0,42   0009: not 0,1 - 0009: brfa 0 [label .L0 @001b] ~ 0010: cpy 0,(2:1) => 0012: cpy 0,(2:2)   001d: ret]`
+
+	if output != expectedOutput {
+		t.Errorf("diff produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
+
+func TestDiffInsertBeforeBranch(t *testing.T) {
+	oldOctets, err := hex.DecodeString("17000000000100000002000000000b00270000000002000000010006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newOctets, err := hex.DecodeString("23000000002a000000" + "17000000000100000002000000000b00270000000002000000010006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Diff(oldOctets, newOctets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", entries)
+
+	const expectedOutput = `[+ 0000: ldi 0,42   0009: not 0,1   0012: brfa 0 [label .L0 @0024]   0019: cpy 0,(2:1)   0024: ret]`
+
+	if output != expectedOutput {
+		t.Errorf("diff produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+
+	if entries[2].Kind != DiffSame || entries[2].Old != "brfa 0 [label .L0 @001b]" {
+		t.Errorf("expected the shifted branch to be unchanged, but received %v", entries[2])
+	}
+}
+
+func TestDiffDifferentCommands(t *testing.T) {
+	entries, err := Diff([]byte{0x06}, []byte{0x08})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", entries)
+
+	const expectedOutput = `[- 0000: ret + 0000: tcall]`
+
+	if output != expectedOutput {
+		t.Errorf("diff produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
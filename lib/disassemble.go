@@ -8,6 +8,7 @@ package swampdisasm_sp
 import (
 	"encoding/binary"
 	"fmt"
+	"sort"
 
 	instruction_sp "github.com/swamp/opcodes/instruction_sp"
 	opcode_sp "github.com/swamp/opcodes/opcode_sp"
@@ -24,344 +25,624 @@ type Argument interface {
 }
 
 type OpcodeInStream struct {
-	position int
-	octets   []byte
+	position   int
+	octets     []byte
+	labels     []*opcode_sp_type.Label
+	labelNames map[uint16]string
 }
 
 func NewOpcodeInStream(octets []byte) *OpcodeInStream {
 	return &OpcodeInStream{octets: octets}
 }
 
+func (s *OpcodeInStream) labelName(pc opcode_sp_type.ProgramCounter) string {
+	return s.labelNames[pc.Value()]
+}
+
 func (s *OpcodeInStream) IsEOF() bool {
 	return s.position >= len(s.octets)
 }
 
-func (s *OpcodeInStream) readUint8() uint8 {
-	if s.position == len(s.octets) {
-		panic("swamp disassembler: read too far")
+func (s *OpcodeInStream) ensureAvailable(octetCount int) error {
+	available := len(s.octets) - s.position
+	if octetCount > available {
+		return fmt.Errorf("swamp disassembler: read too far at %04x. expected %d octets, but only %d available", s.position, octetCount, available)
+	}
+
+	return nil
+}
+
+func (s *OpcodeInStream) readUint8() (uint8, error) {
+	if err := s.ensureAvailable(1); err != nil {
+		return 0, err
 	}
 
 	a := s.octets[s.position]
 
 	s.position++
 
-	return a
+	return a, nil
 }
 
-func (s *OpcodeInStream) readUint16() uint16 {
-	if s.position+2 == len(s.octets) {
-		panic("swamp disassembler: read too far uint16")
+func (s *OpcodeInStream) readUint16() (uint16, error) {
+	if err := s.ensureAvailable(2); err != nil {
+		return 0, err
 	}
 
 	pointer := binary.LittleEndian.Uint16(s.octets[s.position : s.position+2])
 
 	s.position += 2
 
-	return pointer
+	return pointer, nil
 }
 
-func (s *OpcodeInStream) readUint32() uint32 {
-	if s.position+4 == len(s.octets) {
-		panic("swamp disassembler: read too far uint32")
+func (s *OpcodeInStream) readUint32() (uint32, error) {
+	if err := s.ensureAvailable(4); err != nil {
+		return 0, err
 	}
 
 	pointer := binary.LittleEndian.Uint32(s.octets[s.position : s.position+4])
 
 	s.position += 4
 
-	return pointer
+	return pointer, nil
 }
 
-func (s *OpcodeInStream) readCommand() instruction_sp.Commands {
-	return instruction_sp.Commands(s.readUint8())
+func (s *OpcodeInStream) readCommand() (instruction_sp.Commands, error) {
+	cmd, err := s.readUint8()
+
+	return instruction_sp.Commands(cmd), err
 }
 
 func (s *OpcodeInStream) programCounter() opcode_sp_type.ProgramCounter {
 	return opcode_sp_type.NewProgramCounter(uint16(s.position))
 }
 
-func (s *OpcodeInStream) readTypeIDConstant() uint16 {
+func (s *OpcodeInStream) readTypeIDConstant() (uint16, error) {
 	return s.readUint16()
 }
 
-func (s *OpcodeInStream) readCount() int {
-	return int(s.readUint8())
+// readCount reads an item count. The opcode format encodes all counts as a single octet.
+func (s *OpcodeInStream) readCount() (int, error) {
+	count, err := s.readUint8()
+
+	return int(count), err
 }
 
-func (s *OpcodeInStream) readArgOffsetSize() opcode_sp_type.ArgOffsetSize {
-	return opcode_sp_type.ArgOffsetSize{
-		Offset: s.readUint16(),
-		Size:   s.readUint16(),
+// readCountWithItemSize reads an item count and checks that count items of itemOctetSize
+// are available, so a corrupt count is reported before any of the items are read.
+func (s *OpcodeInStream) readCountWithItemSize(itemOctetSize int) (int, error) {
+	count, err := s.readCount()
+	if err != nil {
+		return 0, err
+	}
+
+	expected := count * itemOctetSize
+	available := len(s.octets) - s.position
+	if expected > available {
+		return 0, fmt.Errorf("swamp disassembler: count %d at %04x needs %d octets, but only %d available", count, s.position, expected, available)
 	}
+
+	return count, nil
 }
 
-func (s *OpcodeInStream) readArgOffsetSizeAlign() opcode_sp_type.ArgOffsetSizeAlign {
-	return opcode_sp_type.ArgOffsetSizeAlign{
-		Offset: s.readUint16(),
-		Size:   s.readUint16(),
-		Align:  s.readUint8(),
+func (s *OpcodeInStream) readArgOffsetSize() (opcode_sp_type.ArgOffsetSize, error) {
+	offset, err := s.readUint16()
+	if err != nil {
+		return opcode_sp_type.ArgOffsetSize{}, err
 	}
+
+	size, err := s.readUint16()
+	if err != nil {
+		return opcode_sp_type.ArgOffsetSize{}, err
+	}
+
+	return opcode_sp_type.ArgOffsetSize{
+		Offset: offset,
+		Size:   size,
+	}, nil
 }
 
-func (s *OpcodeInStream) readInt32() int32 {
-	return int32(s.readUint32())
+func (s *OpcodeInStream) readArgOffsetSizeAlign() (opcode_sp_type.ArgOffsetSizeAlign, error) {
+	offsetSize, err := s.readArgOffsetSize()
+	if err != nil {
+		return opcode_sp_type.ArgOffsetSizeAlign{}, err
+	}
+
+	align, err := s.readUint8()
+	if err != nil {
+		return opcode_sp_type.ArgOffsetSizeAlign{}, err
+	}
+
+	return opcode_sp_type.ArgOffsetSizeAlign{
+		Offset: offsetSize.Offset,
+		Size:   offsetSize.Size,
+		Align:  align,
+	}, nil
 }
 
-func (s *OpcodeInStream) readBoolean() bool {
-	return s.readUint8() != 0
+func (s *OpcodeInStream) readInt32() (int32, error) {
+	v, err := s.readUint32()
+
+	return int32(v), err
 }
 
-func (s *OpcodeInStream) readItemSize() opcode_sp_type.StackRange {
-	return opcode_sp_type.StackRange(s.readUint16())
+func (s *OpcodeInStream) readBoolean() (bool, error) {
+	v, err := s.readUint8()
+
+	return v != 0, err
 }
 
-func (s *OpcodeInStream) readAlign() opcode_sp_type.MemoryAlign {
-	return opcode_sp_type.MemoryAlign(s.readUint8())
+func (s *OpcodeInStream) readItemSize() (opcode_sp_type.StackRange, error) {
+	v, err := s.readUint16()
+
+	return opcode_sp_type.StackRange(v), err
 }
 
-func (s *OpcodeInStream) readLabel() *opcode_sp_type.Label {
-	delta := s.readUint16()
+func (s *OpcodeInStream) readAlign() (opcode_sp_type.MemoryAlign, error) {
+	v, err := s.readUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	if v == 0 {
+		return 0, fmt.Errorf("swamp disassembler: we can not allow zero align at %04x", s.position-1)
+	}
+
+	return opcode_sp_type.MemoryAlign(v), nil
+}
+
+func (s *OpcodeInStream) readLabel() (*opcode_sp_type.Label, error) {
+	delta, err := s.readUint16()
+	if err != nil {
+		return nil, err
+	}
+
 	resultingPosition := s.programCounter().Add(delta)
+	label := opcode_sp_type.NewLabelDefined(s.labelName(resultingPosition), resultingPosition)
+	s.labels = append(s.labels, label)
 
-	return opcode_sp_type.NewLabelDefined("", resultingPosition)
+	return label, nil
 }
 
-func (s *OpcodeInStream) readLabelOffset(offset opcode_sp_type.ProgramCounter) *opcode_sp_type.Label {
-	delta := s.readUint16()
+func (s *OpcodeInStream) readLabelOffset(offset opcode_sp_type.ProgramCounter) (*opcode_sp_type.Label, error) {
+	delta, err := s.readUint16()
+	if err != nil {
+		return nil, err
+	}
+
 	resultingPosition := offset.Add(delta)
+	label := opcode_sp_type.NewLabelDefined(s.labelName(resultingPosition), resultingPosition)
+	s.labels = append(s.labels, label)
 
-	return opcode_sp_type.NewLabelDefined("offset", resultingPosition)
+	return label, nil
 }
 
-func (s *OpcodeInStream) readSourceStackPosition() opcode_sp_type.SourceStackPosition {
-	pointer := s.readUint32()
-	return opcode_sp_type.SourceStackPosition(pointer)
+func (s *OpcodeInStream) readSourceStackPosition() (opcode_sp_type.SourceStackPosition, error) {
+	pointer, err := s.readUint32()
+
+	return opcode_sp_type.SourceStackPosition(pointer), err
 }
 
-func (s *OpcodeInStream) readSourceStackPositionRange() opcode_sp_type.SourceStackPositionRange {
-	pointer := s.readUint32()
-	size := s.readUint16()
+func (s *OpcodeInStream) readSourceStackPositionRange() (opcode_sp_type.SourceStackPositionRange, error) {
+	pointer, err := s.readUint32()
+	if err != nil {
+		return opcode_sp_type.SourceStackPositionRange{}, err
+	}
+
+	size, err := s.readUint16()
+	if err != nil {
+		return opcode_sp_type.SourceStackPositionRange{}, err
+	}
+
 	if size == 0 {
-		panic("disassemble: we can not allow zero size in range")
+		return opcode_sp_type.SourceStackPositionRange{}, fmt.Errorf("swamp disassembler: we can not allow zero size in range")
 	}
+
 	return opcode_sp_type.SourceStackPositionRange{
 		Position: opcode_sp_type.SourceStackPosition(pointer),
 		Range:    opcode_sp_type.SourceStackRange(size),
-	}
+	}, nil
 }
 
-func (s *OpcodeInStream) readSourceStackPositions() []opcode_sp_type.SourceStackPosition {
-	count := s.readCount()
+func (s *OpcodeInStream) readSourceStackPositions() ([]opcode_sp_type.SourceStackPosition, error) {
+	count, err := s.readCountWithItemSize(4)
+	if err != nil {
+		return nil, err
+	}
+
 	targetArray := make([]opcode_sp_type.SourceStackPosition, count)
 	for i := 0; i < count; i++ {
-		targetArray[i] = s.readSourceStackPosition()
+		targetArray[i], err = s.readSourceStackPosition()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return targetArray
+	return targetArray, nil
 }
 
-func (s *OpcodeInStream) readTargetStackPosition() opcode_sp_type.TargetStackPosition {
-	pointer := s.readUint32()
-	return opcode_sp_type.TargetStackPosition(pointer)
+func (s *OpcodeInStream) readTargetStackPosition() (opcode_sp_type.TargetStackPosition, error) {
+	pointer, err := s.readUint32()
+
+	return opcode_sp_type.TargetStackPosition(pointer), err
 }
 
-func (s *OpcodeInStream) readSourceDynamicMemoryPosition() opcode_sp_type.SourceDynamicMemoryPosition {
-	pointer := s.readUint32()
-	return opcode_sp_type.SourceDynamicMemoryPosition(pointer)
+func (s *OpcodeInStream) readSourceDynamicMemoryPosition() (opcode_sp_type.SourceDynamicMemoryPosition, error) {
+	pointer, err := s.readUint32()
+
+	return opcode_sp_type.SourceDynamicMemoryPosition(pointer), err
 }
 
-func disassembleListConj(s *OpcodeInStream) *instruction_sp.ListConj {
-	destination := s.readTargetStackPosition()
-	list := s.readSourceStackPosition()
-	item := s.readSourceStackPosition()
-	itemSize := s.readItemSize()
-	itemAlign := s.readAlign()
+func (s *OpcodeInStream) readTargetAndSources() (opcode_sp_type.TargetStackPosition, opcode_sp_type.SourceStackPosition, opcode_sp_type.SourceStackPosition, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return 0, 0, 0, err
+	}
 
-	return instruction_sp.NewListConj(destination, item, itemSize, itemAlign, list)
+	a, err := s.readSourceStackPosition()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	b, err := s.readSourceStackPosition()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return destination, a, b, nil
+}
+
+func disassembleListConj(s *OpcodeInStream) (*instruction_sp.ListConj, error) {
+	destination, list, item, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
+
+	itemSize, err := s.readItemSize()
+	if err != nil {
+		return nil, err
+	}
+
+	itemAlign, err := s.readAlign()
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction_sp.NewListConj(destination, item, itemSize, itemAlign, list), nil
+}
+
+func disassembleListAppend(s *OpcodeInStream) (*instruction_sp.ListAppend, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction_sp.NewListAppend(destination, a, b), nil
+}
+
+func disassembleStringAppend(s *OpcodeInStream) (*instruction_sp.StringAppend, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction_sp.NewStringAppend(destination, a, b), nil
 }
 
-func disassembleListAppend(s *OpcodeInStream) *instruction_sp.ListAppend {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.BinaryOperator, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewListAppend(destination, a, b)
+	return instruction_sp.NewBinaryOperator(cmd, destination, a, b), nil
 }
 
-func disassembleStringAppend(s *OpcodeInStream) *instruction_sp.StringAppend {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleStringBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.BinaryOperator, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewStringAppend(destination, a, b)
+	return instruction_sp.NewBinaryOperator(cmd, destination, a, b), nil
 }
 
-func disassembleBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.BinaryOperator {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleEnumBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.BinaryOperator, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBinaryOperator(cmd, destination, a, b)
+	return instruction_sp.NewBinaryOperator(cmd, destination, a, b), nil
 }
 
-func disassembleStringBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.BinaryOperator {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleBooleanBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.BinaryOperator, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBinaryOperator(cmd, destination, a, b)
+	return instruction_sp.NewBinaryOperator(cmd, destination, a, b), nil
 }
 
-func disassembleEnumBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.BinaryOperator {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleBitwiseOperator(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.BinaryOperator, error) {
+	destination, a, b, err := s.readTargetAndSources()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBinaryOperator(cmd, destination, a, b)
+	return instruction_sp.NewBinaryOperator(cmd, destination, a, b), nil
 }
 
-func disassembleBooleanBinaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.BinaryOperator {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleBitwiseUnaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.IntUnaryOperator, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.readSourceStackPosition()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBinaryOperator(cmd, destination, a, b)
+	return instruction_sp.NewIntUnaryOperator(cmd, destination, a), nil
 }
 
-func disassembleBitwiseOperator(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.BinaryOperator {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
-	b := s.readSourceStackPosition()
+func disassembleLoadInteger(s *OpcodeInStream) (*instruction_sp.LoadInteger, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.readInt32()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBinaryOperator(cmd, destination, a, b)
+	return instruction_sp.NewLoadInteger(destination, a), nil
 }
 
-func disassembleBitwiseUnaryOperator(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.IntUnaryOperator {
-	destination := s.readTargetStackPosition()
-	a := s.readSourceStackPosition()
+func disassembleLoadRune(s *OpcodeInStream) (*instruction_sp.LoadRune, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	shortRune, err := s.readUint8()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewIntUnaryOperator(cmd, destination, a)
+	return instruction_sp.NewLoadRune(destination, instruction_sp.ShortRune(shortRune)), nil
 }
 
-func disassembleLoadInteger(s *OpcodeInStream) *instruction_sp.LoadInteger {
-	destination := s.readTargetStackPosition()
-	a := s.readInt32()
+func disassembleLoadBoolean(s *OpcodeInStream) (*instruction_sp.LoadBool, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.readBoolean()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewLoadInteger(destination, a)
+	return instruction_sp.NewLoadBool(destination, a), nil
 }
 
-func disassembleLoadRune(s *OpcodeInStream) *instruction_sp.LoadRune {
-	destination := s.readTargetStackPosition()
-	shortRune := s.readUint8()
+func disassembleSetEnum(s *OpcodeInStream) (*instruction_sp.SetEnum, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.readUint8()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewLoadRune(destination, instruction_sp.ShortRune(shortRune))
+	itemSize, err := s.readItemSize()
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction_sp.NewSetEnum(destination, a, itemSize), nil
 }
 
-func disassembleLoadBoolean(s *OpcodeInStream) *instruction_sp.LoadBool {
-	destination := s.readTargetStackPosition()
-	a := s.readBoolean()
+func disassembleLoadZeroMemoryPointer(s *OpcodeInStream) (*instruction_sp.LoadZeroMemoryPointer, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.readSourceDynamicMemoryPosition()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewLoadBool(destination, a)
+	return instruction_sp.NewLoadZeroMemoryPointer(destination, source), nil
 }
 
-func disassembleSetEnum(s *OpcodeInStream) *instruction_sp.SetEnum {
-	destination := s.readTargetStackPosition()
-	a := s.readUint8()
-	itemSize := s.readItemSize()
+func (s *OpcodeInStream) readCreateCollection() (opcode_sp_type.TargetStackPosition, opcode_sp_type.StackRange, opcode_sp_type.MemoryAlign, []opcode_sp_type.SourceStackPosition, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	itemSize, err := s.readItemSize()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	memoryAlign, err := s.readAlign()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	arguments, err := s.readSourceStackPositions()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
 
-	return instruction_sp.NewSetEnum(destination, a, itemSize)
+	return destination, itemSize, memoryAlign, arguments, nil
 }
 
-func disassembleLoadZeroMemoryPointer(s *OpcodeInStream) *instruction_sp.LoadZeroMemoryPointer {
-	destination := s.readTargetStackPosition()
-	source := s.readSourceDynamicMemoryPosition()
+func disassembleCreateList(s *OpcodeInStream) (*instruction_sp.CreateList, error) {
+	destination, itemSize, memoryAlign, arguments, err := s.readCreateCollection()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewLoadZeroMemoryPointer(destination, source)
+	return instruction_sp.NewCreateList(destination, itemSize, memoryAlign, arguments), nil
 }
 
-func disassembleCreateList(s *OpcodeInStream) *instruction_sp.CreateList {
-	destination := s.readTargetStackPosition()
-	itemSize := s.readItemSize()
-	memoryAlign := s.readAlign()
-	arguments := s.readSourceStackPositions()
+func disassembleCreateArray(s *OpcodeInStream) (*instruction_sp.CreateArray, error) {
+	destination, itemSize, memoryAlign, arguments, err := s.readCreateCollection()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewCreateList(destination, itemSize, memoryAlign, arguments)
+	return instruction_sp.NewCreateArray(destination, itemSize, memoryAlign, arguments), nil
 }
 
-func disassembleCreateArray(s *OpcodeInStream) *instruction_sp.CreateArray {
-	destination := s.readTargetStackPosition()
-	itemSize := s.readItemSize()
-	memoryAlign := s.readAlign()
-	arguments := s.readSourceStackPositions()
+func (s *OpcodeInStream) readCallTarget() (opcode_sp_type.TargetStackPosition, opcode_sp_type.SourceStackPosition, error) {
+	newStackPointer, err := s.readTargetStackPosition()
+	if err != nil {
+		return 0, 0, err
+	}
 
-	return instruction_sp.NewCreateArray(destination, itemSize, memoryAlign, arguments)
+	functionRegister, err := s.readSourceStackPosition()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return newStackPointer, functionRegister, nil
 }
 
-func disassembleCall(s *OpcodeInStream) *instruction_sp.Call {
-	newStackPointer := s.readTargetStackPosition()
-	functionRegister := s.readSourceStackPosition()
+func disassembleCall(s *OpcodeInStream) (*instruction_sp.Call, error) {
+	newStackPointer, functionRegister, err := s.readCallTarget()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewCall(newStackPointer, functionRegister)
+	return instruction_sp.NewCall(newStackPointer, functionRegister), nil
 }
 
-func disassembleCallExternal(s *OpcodeInStream) *instruction_sp.CallExternal {
-	newStackPointer := s.readTargetStackPosition()
-	functionRegister := s.readSourceStackPosition()
+func disassembleCallExternal(s *OpcodeInStream) (*instruction_sp.CallExternal, error) {
+	newStackPointer, functionRegister, err := s.readCallTarget()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewCallExternal(newStackPointer, functionRegister)
+	return instruction_sp.NewCallExternal(newStackPointer, functionRegister), nil
 }
 
-func disassembleCallExternalWithSizes(s *OpcodeInStream) *instruction_sp.CallExternalWithSizes {
-	newStackPointer := s.readTargetStackPosition()
-	functionRegister := s.readSourceStackPosition()
-	count := s.readCount()
+func disassembleCallExternalWithSizes(s *OpcodeInStream) (*instruction_sp.CallExternalWithSizes, error) {
+	newStackPointer, functionRegister, err := s.readCallTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.readCountWithItemSize(4)
+	if err != nil {
+		return nil, err
+	}
+
 	targetArgs := make([]opcode_sp_type.ArgOffsetSize, count)
 	for i := 0; i < count; i++ {
-		targetArgs[i] = s.readArgOffsetSize()
+		targetArgs[i], err = s.readArgOffsetSize()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return instruction_sp.NewCallExternalWithSizes(newStackPointer, functionRegister, targetArgs)
+	return instruction_sp.NewCallExternalWithSizes(newStackPointer, functionRegister, targetArgs), nil
 }
 
-func disassembleCallExternalWithSizesAlign(s *OpcodeInStream) *instruction_sp.CallExternalWithSizesAlign {
-	newStackPointer := s.readTargetStackPosition()
-	functionRegister := s.readSourceStackPosition()
-	count := s.readCount()
+func disassembleCallExternalWithSizesAlign(s *OpcodeInStream) (*instruction_sp.CallExternalWithSizesAlign, error) {
+	newStackPointer, functionRegister, err := s.readCallTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.readCountWithItemSize(5)
+	if err != nil {
+		return nil, err
+	}
+
 	targetArgs := make([]opcode_sp_type.ArgOffsetSizeAlign, count)
 	for i := 0; i < count; i++ {
-		targetArgs[i] = s.readArgOffsetSizeAlign()
+		targetArgs[i], err = s.readArgOffsetSizeAlign()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return instruction_sp.NewCallExternalWithSizesAlign(newStackPointer, functionRegister, targetArgs)
+	return instruction_sp.NewCallExternalWithSizesAlign(newStackPointer, functionRegister, targetArgs), nil
 }
 
-func disassembleCurry(s *OpcodeInStream) *instruction_sp.Curry {
-	destination := s.readTargetStackPosition()
-	typeIDConstant := s.readTypeIDConstant()
-	firstParameterAlign := s.readAlign()
-	functionRegister := s.readSourceStackPosition()
-	arguments := s.readSourceStackPositionRange()
+func disassembleCurry(s *OpcodeInStream) (*instruction_sp.Curry, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	typeIDConstant, err := s.readTypeIDConstant()
+	if err != nil {
+		return nil, err
+	}
+
+	firstParameterAlign, err := s.readAlign()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewCurry(destination, typeIDConstant, firstParameterAlign, functionRegister, arguments)
+	functionRegister, err := s.readSourceStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	arguments, err := s.readSourceStackPositionRange()
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction_sp.NewCurry(destination, typeIDConstant, firstParameterAlign, functionRegister, arguments), nil
+}
+
+func (s *OpcodeInStream) readCaseLabel(lastLabel *opcode_sp_type.Label) (*opcode_sp_type.Label, error) {
+	if lastLabel != nil {
+		return s.readLabelOffset(lastLabel.DefinedProgramCounter())
+	}
+
+	return s.readLabel()
 }
 
-func disassembleEnumCase(s *OpcodeInStream) *instruction_sp.EnumCase {
-	source := s.readSourceStackPosition()
-	count := s.readCount()
+func disassembleEnumCase(s *OpcodeInStream) (*instruction_sp.EnumCase, error) {
+	source, err := s.readSourceStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.readCountWithItemSize(3)
+	if err != nil {
+		return nil, err
+	}
 
 	var jumps []instruction_sp.EnumCaseJump
 
 	var lastLabel *opcode_sp_type.Label
 
 	for i := 0; i < count; i++ {
-		enumValue := s.readUint8()
-
-		var label *opcode_sp_type.Label
+		enumValue, err := s.readUint8()
+		if err != nil {
+			return nil, err
+		}
 
-		if lastLabel != nil {
-			label = s.readLabelOffset(lastLabel.DefinedProgramCounter())
-		} else {
-			label = s.readLabel()
+		label, err := s.readCaseLabel(lastLabel)
+		if err != nil {
+			return nil, err
 		}
 
 		lastLabel = label
@@ -369,7 +650,7 @@ func disassembleEnumCase(s *OpcodeInStream) *instruction_sp.EnumCase {
 		jumps = append(jumps, jump)
 	}
 
-	return instruction_sp.NewEnumCase(source, jumps)
+	return instruction_sp.NewEnumCase(source, jumps), nil
 }
 
 /*
@@ -383,23 +664,30 @@ func disassembleEnumCase(s *OpcodeInStream) *instruction_sp.EnumCase {
 		panic(fmt.Errorf("unknown matching type %v", cmd))
 	}
 */
-func disassemblePatternMatchingInt(cmd instruction_sp.Commands, s *OpcodeInStream) *instruction_sp.PatternMatchingInt {
-	source := s.readSourceStackPosition()
-	count := s.readCount()
+func disassemblePatternMatchingInt(cmd instruction_sp.Commands, s *OpcodeInStream) (*instruction_sp.PatternMatchingInt, error) {
+	source, err := s.readSourceStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.readCountWithItemSize(6)
+	if err != nil {
+		return nil, err
+	}
 
 	var jumps []instruction_sp.EnumCasePatternMatchingIntJump
 
 	var lastLabel *opcode_sp_type.Label
 
 	for i := 0; i < count; i++ {
-		matchInteger := s.readInt32()
-
-		var label *opcode_sp_type.Label
+		matchInteger, err := s.readInt32()
+		if err != nil {
+			return nil, err
+		}
 
-		if lastLabel != nil {
-			label = s.readLabelOffset(lastLabel.DefinedProgramCounter())
-		} else {
-			label = s.readLabel()
+		label, err := s.readCaseLabel(lastLabel)
+		if err != nil {
+			return nil, err
 		}
 
 		lastLabel = label
@@ -407,47 +695,74 @@ func disassemblePatternMatchingInt(cmd instruction_sp.Commands, s *OpcodeInStrea
 		jumps = append(jumps, jump)
 	}
 
-	defaultLabel := s.readLabelOffset(lastLabel.DefinedProgramCounter())
+	defaultLabel, err := s.readCaseLabel(lastLabel)
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewPatternMatchingInt(source, jumps, defaultLabel)
+	return instruction_sp.NewPatternMatchingInt(source, jumps, defaultLabel), nil
 }
 
-func disassembleMemoryCopy(s *OpcodeInStream) *instruction_sp.MemoryCopy {
-	destination := s.readTargetStackPosition()
-	source := s.readSourceStackPositionRange()
+func disassembleMemoryCopy(s *OpcodeInStream) (*instruction_sp.MemoryCopy, error) {
+	destination, err := s.readTargetStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.readSourceStackPositionRange()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewMemoryCopy(destination, source)
+	return instruction_sp.NewMemoryCopy(destination, source), nil
 }
 
-func disassembleTailCall(s *OpcodeInStream) *instruction_sp.TailCall {
-	return instruction_sp.NewTailCall()
+func disassembleTailCall(s *OpcodeInStream) (*instruction_sp.TailCall, error) {
+	return instruction_sp.NewTailCall(), nil
 }
 
-func disassembleReturn(s *OpcodeInStream) *instruction_sp.Return {
-	return instruction_sp.NewReturn()
+func disassembleReturn(s *OpcodeInStream) (*instruction_sp.Return, error) {
+	return instruction_sp.NewReturn(), nil
 }
 
-func disassembleJump(s *OpcodeInStream) *instruction_sp.Jump {
-	label := s.readLabel()
+func disassembleJump(s *OpcodeInStream) (*instruction_sp.Jump, error) {
+	label, err := s.readLabel()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewJump(label)
+	return instruction_sp.NewJump(label), nil
 }
 
-func disassembleBranchFalse(s *OpcodeInStream) *instruction_sp.BranchFalse {
-	test := s.readSourceStackPosition()
-	label := s.readLabel()
+func disassembleBranchFalse(s *OpcodeInStream) (*instruction_sp.BranchFalse, error) {
+	test, err := s.readSourceStackPosition()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBranchFalse(test, label)
+	label, err := s.readLabel()
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction_sp.NewBranchFalse(test, label), nil
 }
 
-func disassembleBranchTrue(s *OpcodeInStream) *instruction_sp.BranchTrue {
-	test := s.readSourceStackPosition()
-	label := s.readLabel()
+func disassembleBranchTrue(s *OpcodeInStream) (*instruction_sp.BranchTrue, error) {
+	test, err := s.readSourceStackPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := s.readLabel()
+	if err != nil {
+		return nil, err
+	}
 
-	return instruction_sp.NewBranchTrue(test, label)
+	return instruction_sp.NewBranchTrue(test, label), nil
 }
 
-func decodeOpcode(cmd instruction_sp.Commands, s *OpcodeInStream) opcode_sp.Instruction {
+func decodeOpcode(cmd instruction_sp.Commands, s *OpcodeInStream) (opcode_sp.Instruction, error) {
 	switch cmd {
 	case instruction_sp.CmdIntAdd:
 		return disassembleBinaryOperator(cmd, s)
@@ -490,7 +805,7 @@ func decodeOpcode(cmd instruction_sp.Commands, s *OpcodeInStream) opcode_sp.Inst
 	case instruction_sp.CmdPatternMatchingInt:
 		return disassemblePatternMatchingInt(cmd, s)
 	case instruction_sp.CmdPatternMatchingString:
-		panic("not implemented")
+		return nil, fmt.Errorf("swamp disassembler: %v is not implemented", instruction_sp.OpcodeToMnemonic(cmd))
 	case instruction_sp.CmdCopyMemory:
 		return disassembleMemoryCopy(s)
 	case instruction_sp.CmdCall:
@@ -553,27 +868,141 @@ func decodeOpcode(cmd instruction_sp.Commands, s *OpcodeInStream) opcode_sp.Inst
 		return disassembleBooleanBinaryOperator(cmd, s)
 	}
 
-	panic(fmt.Sprintf("swamp disassembler: unknown opcode:%v", cmd))
+	return nil, fmt.Errorf("swamp disassembler: unknown opcode:%v", cmd)
+}
+
+type decodedInstruction struct {
+	pc          opcode_sp_type.ProgramCounter
+	cmd         instruction_sp.Commands
+	instruction opcode_sp.Instruction
+	labels      []*opcode_sp_type.Label
+	octetCount  int
+}
+
+func (d decodedInstruction) String() string {
+	return fmt.Sprintf("%v", d.instruction)
+}
+
+// comment returns an annotation for instructions that do not show all their operands.
+func (d decodedInstruction) comment() string {
+	switch d.cmd {
+	case instruction_sp.CmdReturn, instruction_sp.CmdTailCall:
+		// Neither has operands. A tail call reuses the current frame, so its result ends up
+		// at the start of the frame, just like the result of a return.
+		return "; implicit result at 0"
+	}
+
+	return ""
+}
+
+func appendComment(line string, decoded decodedInstruction) string {
+	comment := decoded.comment()
+	if comment == "" {
+		return line
+	}
+
+	return fmt.Sprintf("%v %v", line, comment)
+}
 
-	// return nil
+func formatLine(decoded decodedInstruction) string {
+	return appendComment(fmt.Sprintf("%04x: %v", decoded.pc.Value(), decoded), decoded)
 }
 
-func Disassemble(octets []byte, verbosity bool) []string {
-	var lines []string
+func decodeInstructionsWithLabelNames(octets []byte, start int, labelNames map[uint16]string, verbosity bool) ([]decodedInstruction, error) {
+	var instructions []decodedInstruction
 
 	s := NewOpcodeInStream(octets)
+	s.position = start
+	s.labelNames = labelNames
 
 	for !s.IsEOF() {
+		startPosition := s.position
 		startPc := s.programCounter()
-		cmd := s.readCommand()
+		s.labels = nil
+		cmd, err := s.readCommand()
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := decodeOpcode(cmd, s)
+		if err != nil {
+			return nil, err
+		}
 
 		if verbosity {
 			log.Printf("disasembling :%s (%02x)\n", instruction_sp.OpcodeToMnemonic(cmd), cmd)
 		}
-		args := decodeOpcode(cmd, s)
-		line := fmt.Sprintf("%04x: %v", startPc.Value(), args)
-		lines = append(lines, line)
+
+		instructions = append(instructions, decodedInstruction{
+			pc: startPc, cmd: cmd, instruction: args, labels: s.labels,
+			octetCount: s.position - startPosition,
+		})
+	}
+
+	return instructions, nil
+}
+
+// resolveLabelNames generates a .LN symbol for every label target, numbered in program counter order.
+func resolveLabelNames(instructions []decodedInstruction) map[uint16]string {
+	var targets []uint16
+
+	seen := make(map[uint16]bool)
+
+	for _, decoded := range instructions {
+		for _, label := range decoded.labels {
+			pc := label.DefinedProgramCounter().Value()
+			if !seen[pc] {
+				seen[pc] = true
+				targets = append(targets, pc)
+			}
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i] < targets[j]
+	})
+
+	labelNames := make(map[uint16]string, len(targets))
+	for i, pc := range targets {
+		labelNames[pc] = fmt.Sprintf(".L%d", i)
+	}
+
+	return labelNames
+}
+
+// decodeInstructionRange decodes the octets from start up to end twice. The first pass finds all label targets,
+// and the second pass creates the labels with their resolved names. Program counters are relative to the
+// start of octets, not to start.
+func decodeInstructionRange(octets []byte, start int, end int, verbosity bool) ([]decodedInstruction, error) {
+	if start < 0 || start > end || end > len(octets) {
+		return nil, fmt.Errorf("swamp disassembler: illegal range %04x-%04x for %d octets", start, end, len(octets))
+	}
+
+	rangeOctets := octets[:end]
+
+	unresolved, err := decodeInstructionsWithLabelNames(rangeOctets, start, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeInstructionsWithLabelNames(rangeOctets, start, resolveLabelNames(unresolved), verbosity)
+}
+
+func decodeInstructions(octets []byte, verbosity bool) ([]decodedInstruction, error) {
+	return decodeInstructionRange(octets, 0, len(octets), verbosity)
+}
+
+func Disassemble(octets []byte, verbosity bool) ([]string, error) {
+	lines := []string{}
+
+	instructions, err := decodeInstructions(octets, verbosity)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, decoded := range instructions {
+		lines = append(lines, formatLine(decoded))
 	}
 
-	return lines
+	return lines, nil
 }
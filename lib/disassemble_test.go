@@ -9,6 +9,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"testing"
+
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+	opcode_sp "github.com/swamp/opcodes/opcode_sp"
+	opcode_sp_type "github.com/swamp/opcodes/type"
 )
 
 func TestSomething(t *testing.T) {
@@ -18,10 +22,14 @@ func TestSomething(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	stringLines := Disassemble(octets, true)
+	stringLines, err := Disassemble(octets, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	output := fmt.Sprintf("%v", stringLines)
 
-	const expectedOutput = `[0000: not 0,1 0009: brfa 0 [label @001b] 0010: cpy 0,(2:1) 001b: ret]`
+	const expectedOutput = `[0000: not 0,1 0009: brfa 0 [label .L0 @001b] 0010: cpy 0,(2:1) 001b: ret ; implicit result at 0]`
 
 	fmt.Println(output)
 
@@ -29,3 +37,136 @@ func TestSomething(t *testing.T) {
 		t.Errorf("disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
 	}
 }
+
+func TestCreateListMaximumCount(t *testing.T) {
+	arguments := make([]opcode_sp_type.SourceStackPosition, 255)
+	for i := range arguments {
+		arguments[i] = opcode_sp_type.SourceStackPosition(i * 4)
+	}
+
+	createList := instruction_sp.NewCreateList(opcode_sp_type.TargetStackPosition(0), 4, 4, arguments)
+
+	stream := opcode_sp.NewOpCodeStream()
+	if err := createList.Write(stream); err != nil {
+		t.Fatal(err)
+	}
+
+	stringLines, err := Disassemble(stream.Octets(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", stringLines)
+
+	expectedOutput := fmt.Sprintf("[0000: %v]", createList)
+
+	if output != expectedOutput {
+		t.Errorf("disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
+
+func TestCreateListTruncatedArguments(t *testing.T) {
+	s := "1e00000000040004ff0000000004000000"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Disassemble(octets, false)
+	if err == nil {
+		t.Fatal("expected error for a count that is larger than the remaining octets")
+	}
+
+	const expectedError = "swamp disassembler: count 255 at 0009 needs 1020 octets, but only 8 available"
+
+	if err.Error() != expectedError {
+		t.Errorf("wrong error. expected\n%s\nbut received\n%s\n", expectedError, err)
+	}
+}
+
+func TestEmptyOctets(t *testing.T) {
+	isEmpty := func(name string, stringLines []string, err error) {
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		if stringLines == nil || len(stringLines) != 0 {
+			t.Errorf("%v: expected an empty slice, but received %v", name, stringLines)
+		}
+	}
+
+	stringLines, err := Disassemble([]byte{}, false)
+	isEmpty("Disassemble", stringLines, err)
+
+	stringLines, err = DisassembleFiltered([]byte{}, IsArithmetic)
+	isEmpty("DisassembleFiltered", stringLines, err)
+
+	stringLines, err = DisassembleWithMnemonics([]byte{}, nil)
+	isEmpty("DisassembleWithMnemonics", stringLines, err)
+
+	stringLines, err = DisassembleWithSourceMap([]byte{}, nil, false)
+	isEmpty("DisassembleWithSourceMap", stringLines, err)
+
+	functions, err := DisassembleFunctionsParallel([]byte{}, []FunctionRange{{Start: 0, End: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isEmpty("DisassembleFunctionsParallel", functions[0], nil)
+}
+
+func TestCommandWithoutOperands(t *testing.T) {
+	_, err := Disassemble([]byte{byte(instruction_sp.CmdIntAdd)}, false)
+	if err == nil {
+		t.Fatal("expected error for a command without its operands")
+	}
+
+	const expectedError = "swamp disassembler: read too far at 0001. expected 4 octets, but only 0 available"
+
+	if err.Error() != expectedError {
+		t.Errorf("wrong error. expected\n%s\nbut received\n%s\n", expectedError, err)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, err := Disassemble([]byte{0xff}, false)
+	if err == nil {
+		t.Fatal("expected error for an unknown command")
+	}
+}
+
+func TestZeroAlign(t *testing.T) {
+	s := "1e00000000040000000000"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Disassemble(octets, false)
+	if err == nil {
+		t.Fatal("expected error for a zero memory align")
+	}
+
+	const expectedError = "swamp disassembler: we can not allow zero align at 0007"
+
+	if err.Error() != expectedError {
+		t.Errorf("wrong error. expected\n%s\nbut received\n%s\n", expectedError, err)
+	}
+}
+
+func TestImplicitResultComments(t *testing.T) {
+	stringLines, err := Disassemble([]byte{byte(instruction_sp.CmdTailCall), byte(instruction_sp.CmdReturn)}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", stringLines)
+
+	const expectedOutput = `[0000: tcall ; implicit result at 0 0001: ret ; implicit result at 0]`
+
+	if output != expectedOutput {
+		t.Errorf("disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
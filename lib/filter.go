@@ -0,0 +1,86 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+)
+
+func IsArithmetic(cmd instruction_sp.Commands) bool {
+	switch cmd {
+	case instruction_sp.CmdIntAdd, instruction_sp.CmdIntSub, instruction_sp.CmdIntDiv,
+		instruction_sp.CmdIntRemainder, instruction_sp.CmdIntMul, instruction_sp.CmdIntNegate,
+		instruction_sp.CmdFixedDiv, instruction_sp.CmdFixedMul:
+		return true
+	}
+
+	return false
+}
+
+func IsComparison(cmd instruction_sp.Commands) bool {
+	switch cmd {
+	case instruction_sp.CmdIntEqual, instruction_sp.CmdIntNotEqual, instruction_sp.CmdIntLess,
+		instruction_sp.CmdIntLessOrEqual, instruction_sp.CmdIntGreater, instruction_sp.CmdIntGreaterOrEqual,
+		instruction_sp.CmdStringEqual, instruction_sp.CmdStringNotEqual,
+		instruction_sp.CmdEnumEqual, instruction_sp.CmdEnumNotEqual,
+		instruction_sp.CmdBoolEqual, instruction_sp.CmdBoolNotEqual:
+		return true
+	}
+
+	return false
+}
+
+func IsBitwise(cmd instruction_sp.Commands) bool {
+	switch cmd {
+	case instruction_sp.CmdIntBitwiseAnd, instruction_sp.CmdIntBitwiseOr, instruction_sp.CmdIntBitwiseXor,
+		instruction_sp.CmdIntBitwiseNot, instruction_sp.CmdIntBitwiseShiftLeft, instruction_sp.CmdIntBitwiseShiftRight:
+		return true
+	}
+
+	return false
+}
+
+func IsControlFlow(cmd instruction_sp.Commands) bool {
+	switch cmd {
+	case instruction_sp.CmdEnumCase, instruction_sp.CmdPatternMatchingInt, instruction_sp.CmdPatternMatchingString,
+		instruction_sp.CmdJump, instruction_sp.CmdBranchFalse, instruction_sp.CmdBranchTrue,
+		instruction_sp.CmdCall, instruction_sp.CmdCallExternal, instruction_sp.CmdCallExternalWithSizes,
+		instruction_sp.CmdCallExternalWithSizesAlign, instruction_sp.CmdTailCall, instruction_sp.CmdReturn:
+		return true
+	}
+
+	return false
+}
+
+func IsCollection(cmd instruction_sp.Commands) bool {
+	switch cmd {
+	case instruction_sp.CmdCreateList, instruction_sp.CmdCreateArray, instruction_sp.CmdListConj,
+		instruction_sp.CmdListAppend, instruction_sp.CmdStringAppend:
+		return true
+	}
+
+	return false
+}
+
+// DisassembleFiltered decodes the whole octet stream, so that the program counters stay correct,
+// but only returns the lines for the commands that match the predicate.
+func DisassembleFiltered(octets []byte, pred func(instruction_sp.Commands) bool) ([]string, error) {
+	instructions, err := decodeInstructions(octets, false)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+
+	for _, decoded := range instructions {
+		if !pred(decoded.cmd) {
+			continue
+		}
+		lines = append(lines, formatLine(decoded))
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,32 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestDisassembleFiltered(t *testing.T) {
+	octets, err := hex.DecodeString("23000000002a0000001700000000010000000a08000000000000000400000006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stringLines, err := DisassembleFiltered(octets, IsArithmetic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", stringLines)
+
+	const expectedOutput = `[0012: addi 8,0,4]`
+
+	if output != expectedOutput {
+		t.Errorf("filter produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
@@ -0,0 +1,58 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"fmt"
+	"strings"
+
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+)
+
+// MnemonicNames overrides the mnemonic for individual commands. Commands that are not
+// in the map use the name from instruction_sp.OpcodeToMnemonic.
+type MnemonicNames map[instruction_sp.Commands]string
+
+func (n MnemonicNames) name(cmd instruction_sp.Commands) string {
+	if name, found := n[cmd]; found {
+		return name
+	}
+
+	return instruction_sp.OpcodeToMnemonic(cmd)
+}
+
+// operands returns the instruction text without the mnemonic that the instruction itself chose.
+func (d decodedInstruction) operands() string {
+	parts := strings.SplitN(d.String(), " ", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return parts[1]
+}
+
+func formatLineWithMnemonic(decoded decodedInstruction, names MnemonicNames) string {
+	line := fmt.Sprintf("%04x: %-8s %v", decoded.pc.Value(), names.name(decoded.cmd), decoded.operands())
+
+	return appendComment(strings.TrimRight(line, " "), decoded)
+}
+
+// DisassembleWithMnemonics formats every line as a mnemonic column followed by the operands.
+// The mnemonic always comes from OpcodeToMnemonic, or from names if the command is overridden.
+func DisassembleWithMnemonics(octets []byte, names MnemonicNames) ([]string, error) {
+	instructions, err := decodeInstructions(octets, false)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+
+	for _, decoded := range instructions {
+		lines = append(lines, formatLineWithMnemonic(decoded, names))
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,41 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+)
+
+func TestDisassembleWithMnemonics(t *testing.T) {
+	s := "17000000000100000002000000000b00270000000002000000010006"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := MnemonicNames{instruction_sp.CmdCopyMemory: "copy"}
+
+	stringLines, err := DisassembleWithMnemonics(octets, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := strings.Join(stringLines, "\n")
+
+	const expectedOutput = `0000: not      0,1
+0009: bne      0 [label .L0 @001b]
+0010: copy     0,(2:1)
+001b: ret ; implicit result at 0`
+
+	if output != expectedOutput {
+		t.Errorf("disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
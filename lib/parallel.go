@@ -0,0 +1,89 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// FunctionRange is the octets of a single function, from Start up to, but not including, End.
+type FunctionRange struct {
+	Start int
+	End   int
+}
+
+func (r FunctionRange) String() string {
+	return fmt.Sprintf("%04x-%04x", r.Start, r.End)
+}
+
+func disassembleFunction(octets []byte, functionRange FunctionRange) ([]string, error) {
+	instructions, err := decodeInstructionRange(octets, functionRange.Start, functionRange.End, false)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+
+	for _, decoded := range instructions {
+		lines = append(lines, formatLine(decoded))
+	}
+
+	return lines, nil
+}
+
+// DisassembleFunctionsParallel disassembles each function range concurrently, using one worker per CPU.
+func DisassembleFunctionsParallel(octets []byte, ranges []FunctionRange) ([][]string, error) {
+	return DisassembleFunctionsParallelWithWorkers(octets, ranges, runtime.NumCPU())
+}
+
+// DisassembleFunctionsParallelWithWorkers disassembles each function range using at most workerCount
+// goroutines. The lines for each function are returned in the same order as the ranges. Program counters
+// are relative to the start of octets, while label names are numbered per function.
+func DisassembleFunctionsParallelWithWorkers(octets []byte, ranges []FunctionRange, workerCount int) ([][]string, error) {
+	if workerCount < 1 {
+		return nil, fmt.Errorf("swamp disassembler: worker count must be at least one, but was %d", workerCount)
+	}
+
+	if workerCount > len(ranges) {
+		workerCount = len(ranges)
+	}
+
+	results := make([][]string, len(ranges))
+	errs := make([]error, len(ranges))
+
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < workerCount; worker++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for index := range indices {
+				results[index], errs[index] = disassembleFunction(octets, ranges[index])
+			}
+		}()
+	}
+
+	for index := range ranges {
+		indices <- index
+	}
+
+	close(indices)
+	wg.Wait()
+
+	for index, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("function %v: %w", ranges[index], err)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,65 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestDisassembleFunctionsParallel(t *testing.T) {
+	s := "17000000000100000002000000000b00270000000002000000010006" + "23000000002a00000006"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ranges := []FunctionRange{{Start: 0x00, End: 0x1c}, {Start: 0x1c, End: 0x26}}
+
+	functions, err := DisassembleFunctionsParallelWithWorkers(octets, ranges, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", functions)
+
+	const expectedOutput = `[[0000: not 0,1 0009: brfa 0 [label .L0 @001b] 0010: cpy 0,(2:1) 001b: ret ; implicit result at 0] [001c: ldi 0,42 0025: ret ; implicit result at 0]]`
+
+	if output != expectedOutput {
+		t.Errorf("parallel disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
+
+func TestDisassembleFunctionsParallelError(t *testing.T) {
+	s := "17000000000100000002000000000b00270000000002000000010006" + "23000000002a"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ranges := []FunctionRange{{Start: 0x00, End: 0x1c}, {Start: 0x1c, End: 0x22}}
+
+	_, err = DisassembleFunctionsParallelWithWorkers(octets, ranges, 4)
+	if err == nil {
+		t.Fatal("expected error for a truncated function")
+	}
+
+	const expectedError = "function 001c-0022: swamp disassembler: read too far at 0021. expected 4 octets, but only 1 available"
+
+	if err.Error() != expectedError {
+		t.Errorf("wrong error. expected\n%s\nbut received\n%s\n", expectedError, err)
+	}
+}
+
+func TestDisassembleFunctionsParallelIllegalWorkerCount(t *testing.T) {
+	_, err := DisassembleFunctionsParallelWithWorkers([]byte{0x06}, []FunctionRange{{Start: 0, End: 1}}, 0)
+	if err == nil {
+		t.Fatal("expected error for a worker count of zero")
+	}
+}
@@ -0,0 +1,89 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SourceMapEntry maps the program counters from StartPC up to, but not including, EndPC to a source line.
+type SourceMapEntry struct {
+	StartPC int
+	EndPC   int
+	File    string
+	Line    int
+}
+
+func (e SourceMapEntry) String() string {
+	return fmt.Sprintf("%v:%d", e.File, e.Line)
+}
+
+type sourceMap struct {
+	entries []SourceMapEntry
+}
+
+func newSourceMap(entries []SourceMapEntry) *sourceMap {
+	sorted := make([]SourceMapEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartPC < sorted[j].StartPC
+	})
+
+	return &sourceMap{entries: sorted}
+}
+
+// lookup returns the innermost entry that contains pc, that is the one that starts last,
+// and if several start at the same program counter, the one that ends first.
+func (m *sourceMap) lookup(pc int) *SourceMapEntry {
+	startedCount := sort.Search(len(m.entries), func(i int) bool {
+		return m.entries[i].StartPC > pc
+	})
+
+	var innermost *SourceMapEntry
+
+	for i := 0; i < startedCount; i++ {
+		entry := &m.entries[i]
+		if pc >= entry.EndPC {
+			continue
+		}
+
+		if innermost == nil || entry.StartPC > innermost.StartPC ||
+			(entry.StartPC == innermost.StartPC && entry.EndPC < innermost.EndPC) {
+			innermost = entry
+		}
+	}
+
+	return innermost
+}
+
+// DisassembleWithSourceMap works like Disassemble, but emits a `; file:line` comment line
+// each time the innermost range in the source map that contains the program counter changes.
+// That includes returning to an enclosing range after a nested one ends.
+func DisassembleWithSourceMap(octets []byte, sourceMapEntries []SourceMapEntry, verbosity bool) ([]string, error) {
+	instructions, err := decodeInstructions(octets, verbosity)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := newSourceMap(sourceMapEntries)
+
+	lines := []string{}
+
+	var lastEntry *SourceMapEntry
+
+	for _, decoded := range instructions {
+		entry := lookup.lookup(int(decoded.pc.Value()))
+		if entry != nil && entry != lastEntry {
+			lines = append(lines, fmt.Sprintf("; %v", entry))
+		}
+
+		lastEntry = entry
+		lines = append(lines, formatLine(decoded))
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,74 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDisassembleWithSourceMap(t *testing.T) {
+	s := "17000000000100000002000000000b00270000000002000000010006"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceMap := []SourceMapEntry{
+		{StartPC: 0x10, EndPC: 0x1c, File: "main.swamp", Line: 7},
+		{StartPC: 0x00, EndPC: 0x10, File: "main.swamp", Line: 3},
+	}
+
+	stringLines, err := DisassembleWithSourceMap(octets, sourceMap, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", stringLines)
+
+	const expectedOutput = `[; main.swamp:3 0000: not 0,1 0009: brfa 0 [label .L0 @001b] ; main.swamp:7 0010: cpy 0,(2:1) 001b: ret ; implicit result at 0]`
+
+	if output != expectedOutput {
+		t.Errorf("disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
+
+func TestDisassembleWithNestedSourceMap(t *testing.T) {
+	s := "17000000000100000002000000000b00270000000002000000010006" + "23000000002a000000"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceMap := []SourceMapEntry{
+		{StartPC: 0x09, EndPC: 0x10, File: "a.swamp", Line: 2},
+		{StartPC: 0x00, EndPC: 0x1c, File: "a.swamp", Line: 1},
+	}
+
+	stringLines, err := DisassembleWithSourceMap(octets, sourceMap, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := strings.Join(stringLines, "\n")
+
+	const expectedOutput = `; a.swamp:1
+0000: not 0,1
+; a.swamp:2
+0009: brfa 0 [label .L0 @001b]
+; a.swamp:1
+0010: cpy 0,(2:1)
+001b: ret ; implicit result at 0
+001c: ldi 0,42`
+
+	if output != expectedOutput {
+		t.Errorf("disassemble produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
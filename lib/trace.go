@@ -0,0 +1,38 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"fmt"
+
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+)
+
+// InstructionSpan is the octets consumed when decoding a single instruction, including the command octet.
+type InstructionSpan struct {
+	PC         int
+	Command    instruction_sp.Commands
+	ByteLength int
+}
+
+func (s InstructionSpan) String() string {
+	return fmt.Sprintf("%04x: %02x (%d)", s.PC, uint8(s.Command), s.ByteLength)
+}
+
+// DecodeTrace returns the span of every decoded instruction, in program counter order.
+func DecodeTrace(octets []byte) ([]InstructionSpan, error) {
+	instructions, err := decodeInstructions(octets, false)
+	if err != nil {
+		return nil, err
+	}
+
+	spans := make([]InstructionSpan, len(instructions))
+	for i, decoded := range instructions {
+		spans[i] = InstructionSpan{PC: int(decoded.pc.Value()), Command: decoded.cmd, ByteLength: decoded.octetCount}
+	}
+
+	return spans, nil
+}
@@ -0,0 +1,116 @@
+//go:build go1.18
+// +build go1.18
+
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+	opcode_sp "github.com/swamp/opcodes/opcode_sp"
+)
+
+// encodeInstruction writes the instruction to a new stream. It returns a nil stream
+// if the encoder panics on a value it does not allow.
+func encodeInstruction(decoded decodedInstruction) (stream *opcode_sp.OpCodeStream, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stream = nil
+			err = nil
+		}
+	}()
+
+	stream = opcode_sp.NewOpCodeStream()
+	if err := decoded.instruction.Write(stream); err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+func FuzzDecodeTrace(f *testing.F) {
+	for _, s := range []string{
+		"17000000000100000002000000000b00270000000002000000010006",
+		"23000000002a0000001700000000010000000a08000000000000000400000006",
+		"04090023000000002a0000000617000000000100000006",
+	} {
+		octets, err := hex.DecodeString(s)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(octets)
+	}
+
+	f.Fuzz(func(t *testing.T, octets []byte) {
+		spans, err := DecodeTrace(octets)
+		if err != nil {
+			return
+		}
+
+		expectedPC := 0
+
+		for _, span := range spans {
+			if span.PC != expectedPC {
+				t.Fatalf("span %v does not start where the previous one ended (%04x)", span, expectedPC)
+			}
+
+			if span.ByteLength < 1 {
+				t.Fatalf("span %v is empty", span)
+			}
+
+			expectedPC += span.ByteLength
+		}
+
+		if expectedPC != len(octets) {
+			t.Fatalf("spans cover %d octets, but input is %d octets", expectedPC, len(octets))
+		}
+
+		instructions, err := decodeInstructions(octets, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Encoding the instruction again is independent of the decoder, so it must produce
+		// the same octets as the decoder consumed, apart from the label deltas.
+		for i, decoded := range instructions {
+			span := spans[i]
+
+			stream, err := encodeInstruction(decoded)
+			if err != nil {
+				t.Fatalf("span %v can not be encoded: %v", span, err)
+			}
+
+			if stream == nil {
+				// The encoder refuses values that the decoder still lists, like a zero memory align
+				continue
+			}
+
+			encoded := stream.Octets()
+			if len(encoded) != span.ByteLength {
+				t.Fatalf("span %v decoded %d octets, but %v encodes to %d octets", span, span.ByteLength, decoded, len(encoded))
+			}
+
+			original := octets[span.PC : span.PC+span.ByteLength]
+			for _, inject := range stream.LabelInjects() {
+				position := int(inject.LocatedAtPosition().Value())
+				copy(encoded[position:position+opcode_sp.OctetSizeOfLabel], original[position:position+opcode_sp.OctetSizeOfLabel])
+			}
+
+			// The encoder writes every true as 1, so other non-zero boolean octets can not be reproduced
+			if decoded.cmd == instruction_sp.CmdLoadBoolean && original[len(original)-1] > 1 {
+				continue
+			}
+
+			if !bytes.Equal(encoded, original) {
+				t.Fatalf("span %v decoded from %x, but %v encodes to %x", span, original, decoded, encoded)
+			}
+		}
+	})
+}
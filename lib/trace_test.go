@@ -0,0 +1,34 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestDecodeTrace(t *testing.T) {
+	s := "17000000000100000002000000000b00270000000002000000010006"
+
+	octets, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := DecodeTrace(octets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := fmt.Sprintf("%v", spans)
+
+	const expectedOutput = `[0000: 17 (9) 0009: 02 (7) 0010: 27 (11) 001b: 06 (1)]`
+
+	if output != expectedOutput {
+		t.Errorf("decode trace produced wrong output. expected\n%s\nbut received\n%s\n", expectedOutput, output)
+	}
+}
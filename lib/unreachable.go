@@ -0,0 +1,114 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"fmt"
+
+	instruction_sp "github.com/swamp/opcodes/instruction_sp"
+)
+
+// Range is a span of program counters from Start up to, but not including, End.
+type Range struct {
+	Start int
+	End   int
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%04x-%04x", r.Start, r.End)
+}
+
+func fallsThrough(cmd instruction_sp.Commands) bool {
+	switch cmd {
+	case instruction_sp.CmdJump, instruction_sp.CmdEnumCase, instruction_sp.CmdPatternMatchingInt,
+		instruction_sp.CmdPatternMatchingString, instruction_sp.CmdReturn, instruction_sp.CmdTailCall:
+		return false
+	}
+
+	return true
+}
+
+// successors returns the program counters that can execute directly after the instruction.
+func successors(decoded decodedInstruction) []int {
+	var targets []int
+
+	if fallsThrough(decoded.cmd) {
+		targets = append(targets, int(decoded.pc.Value())+decoded.octetCount)
+	}
+
+	for _, label := range decoded.labels {
+		targets = append(targets, int(label.DefinedProgramCounter().Value()))
+	}
+
+	return targets
+}
+
+// UnreachableRanges follows the fall through and branch edges from entryPC and returns
+// the ranges of instructions that can never be reached.
+func UnreachableRanges(octets []byte, entryPC int) ([]Range, error) {
+	instructions, err := decodeInstructions(octets, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(instructions) == 0 {
+		return []Range{}, nil
+	}
+
+	indexForPC := make(map[int]int, len(instructions))
+	for i, decoded := range instructions {
+		indexForPC[int(decoded.pc.Value())] = i
+	}
+
+	entryIndex, found := indexForPC[entryPC]
+	if !found {
+		return nil, fmt.Errorf("swamp disassembler: entry %04x is not the start of an instruction", entryPC)
+	}
+
+	reachable := make([]bool, len(instructions))
+	reachable[entryIndex] = true
+	pending := []int{entryIndex}
+
+	for len(pending) > 0 {
+		index := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, target := range successors(instructions[index]) {
+			targetIndex, found := indexForPC[target]
+			if !found {
+				if target == len(octets) {
+					continue
+				}
+
+				return nil, fmt.Errorf("swamp disassembler: %04x branches to %04x which is not the start of an instruction", instructions[index].pc.Value(), target)
+			}
+
+			if !reachable[targetIndex] {
+				reachable[targetIndex] = true
+				pending = append(pending, targetIndex)
+			}
+		}
+	}
+
+	ranges := []Range{}
+
+	for i, decoded := range instructions {
+		if reachable[i] {
+			continue
+		}
+
+		start := int(decoded.pc.Value())
+		end := start + decoded.octetCount
+
+		if len(ranges) > 0 && ranges[len(ranges)-1].End == start {
+			ranges[len(ranges)-1].End = end
+		} else {
+			ranges = append(ranges, Range{Start: start, End: end})
+		}
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,71 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swampdisasm_sp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestUnreachableRanges(t *testing.T) {
+	tests := []struct {
+		name           string
+		octets         string
+		entryPC        int
+		expectedOutput string
+		expectedError  string
+	}{
+		{name: "jmp", octets: "04090023000000002a0000000617000000000100000006", expectedOutput: "[0003-000c 000d-0017]"},
+		{name: "brfa", octets: "02000000000a000623000000002a00000006", expectedOutput: "[0008-0011]"},
+		{name: "brtr", octets: "03000000000a000623000000002a00000006", expectedOutput: "[0008-0011]"},
+		{name: "jmpe", octets: "010000000002000c0001010023000000002a0000000606", expectedOutput: "[000c-0015]"},
+		{name: "jmppmi", octets: "2c0000000001070000000b00010023000000002a0000000606", expectedOutput: "[000e-0017]"},
+		{name: "tcall", octets: "0806", expectedOutput: "[0001-0002]"},
+		{name: "all reachable", octets: "23000000002a00000006", expectedOutput: "[]"},
+		{name: "empty", octets: "", expectedOutput: "[]"},
+		{
+			name: "entry inside instruction", octets: "23000000002a00000006", entryPC: 1,
+			expectedError: "swamp disassembler: entry 0001 is not the start of an instruction",
+		},
+		{
+			name: "branch inside instruction", octets: "04010023000000002a00000006",
+			expectedError: "swamp disassembler: 0000 branches to 0004 which is not the start of an instruction",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			octets, err := hex.DecodeString(test.octets)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ranges, err := UnreachableRanges(octets, test.entryPC)
+			if test.expectedError != "" {
+				if err == nil || err.Error() != test.expectedError {
+					t.Fatalf("wrong error. expected\n%s\nbut received\n%v\n", test.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ranges == nil {
+				t.Fatal("expected a non-nil slice")
+			}
+
+			output := fmt.Sprintf("%v", ranges)
+
+			if output != test.expectedOutput {
+				t.Errorf("unreachable ranges wrong. expected\n%s\nbut received\n%s\n", test.expectedOutput, output)
+			}
+		})
+	}
+}